@@ -1,208 +1,202 @@
 package metgo
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"sync/atomic"
 	"time"
 )
 
 type MetNoService struct {
-	siteName               string
-	cacheDir               string
-	logger                 *slog.Logger
-	locationForecastCaches []Cache[LocationforecastResult]
+	siteName    string
+	cacheDir    string
+	logger      *slog.Logger
+	httpClient  *http.Client
+	clock       func() time.Time
+	userAgent   string
+	cachePolicy CachePolicy
+	// defaultTTL is used to compute Expires when a response has neither a Cache-Control
+	// max-age nor an Expires header.
+	defaultTTL time.Duration
+	// staleWhileRevalidate, if true, serves expired cache data (with a warning logged)
+	// instead of an error when refreshing it from the api fails.
+	staleWhileRevalidate bool
+	// offline, when set via SetOffline, makes every product serve whatever is in the
+	// cache, however stale, without ever calling the api.
+	offline atomic.Bool
+
+	locationForecastCaches     []Cache[LocationforecastResult]
+	locationForecastEndpoint   *endpoint[LocationforecastResult]
+	nowcastEndpoint            *endpoint[NowcastResult]
+	sunriseEndpoint            *endpoint[SunriseResult]
+	airQualityForecastEndpoint *endpoint[AirQualityForecastResult]
+	metAlertsEndpoint          *endpoint[MetAlertsResult]
+
+	prefetcher *prefetcher
 }
 
-// Method to create a new service to interact with the met.no api.
-func NewMetNoService(siteName string, cacheDirectory string, logger *slog.Logger) (*MetNoService, error) {
-	if siteName == "" {
-		return nil, fmt.Errorf("siteName must be defined")
-	}
-	if logger == nil {
-		logger = slog.New(discardHandler{})
-	}
-	service := &MetNoService{
-		siteName: siteName,
-		cacheDir: cacheDirectory,
-		logger:   logger,
-		// Caches should be ordered from most to least volatile (or performant)
-		locationForecastCaches: []Cache[LocationforecastResult]{
-			&MemoryCache[LocationforecastResult]{},
-			&DiskCache[LocationforecastResult]{CacheDirectory: cacheDirectory},
-		},
+// Option configures a MetNoService created via NewMetNoService.
+type Option func(*MetNoService)
+
+// WithLogger sets the logger used by the service. Defaults to a no-op logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *MetNoService) {
+		s.logger = logger
 	}
-	return service, nil
 }
 
-// Get a locationforecast result.
-func (s *MetNoService) Locationforecast(lat float64, lon float64, alt int) (*LocationforecastResult, error) {
-	// Prepare the cache name
-	cacheName := s.buildLocationforecastCacheName(lat, lon, alt)
-
-	// Try get the data from one of the caches
-	cacheObject, cacheInfo, err := getDataFromCaches(s, s.locationForecastCaches, cacheName)
-	if err != nil {
-		return nil, err
-	}
-	// If we have a cache object which is not expired, return it
-	if cacheObject != nil && !isExpired(cacheInfo.Expires) {
-		s.logger.Debug("Found valid data in cache")
-		return cacheObject, nil
+// WithCacheDirectory sets the directory used by the default disk caches. It has no
+// effect on a product whose cache chain was replaced via WithCaches.
+func WithCacheDirectory(cacheDirectory string) Option {
+	return func(s *MetNoService) {
+		s.cacheDir = cacheDirectory
 	}
+}
 
-	// No data somewhere else, so get the data from the api
-	url := fmt.Sprintf("https://api.met.no/weatherapi/locationforecast/2.0/complete?lat=%.4f&lon=%.4f&altitude=%d", lat, lon, alt)
-	apiCacheObject, apiCacheInfoObject, err := loadDataFromApi(s, url, cacheObject, cacheInfo)
-	if err != nil {
-		return nil, err
+// WithCaches replaces the default locationforecast cache chain, ordered from
+// most to least volatile (or performant).
+func WithCaches(caches []Cache[LocationforecastResult]) Option {
+	return func(s *MetNoService) {
+		s.locationForecastCaches = caches
 	}
-	s.logger.Debug("Loaded from api")
+}
 
-	// Update the caches
-	for _, cache := range s.locationForecastCaches {
-		if err := cache.SetCache(cacheName, apiCacheObject, apiCacheInfoObject); err != nil {
-			return nil, err
-		}
+// WithHTTPClient sets the http.Client used to call the met.no api. Defaults to http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *MetNoService) {
+		s.httpClient = client
 	}
-
-	// Return the objec
-	return apiCacheObject, nil
 }
 
-func (s *MetNoService) buildLocationforecastCacheName(lat float64, lon float64, alt int) string {
-	return fmt.Sprintf("locationforecast-%.4f-%.4f-%d", lat, lon, alt)
+// WithClock overrides the function used to determine the current time, mainly useful for tests.
+func WithClock(clock func() time.Time) Option {
+	return func(s *MetNoService) {
+		s.clock = clock
+	}
 }
 
-////////////////////////////////////////////////////////////
-// Helper methods
-////////////////////////////////////////////////////////////
-
-func isExpired(checkDate time.Time) bool {
-	return time.Now().After(checkDate)
+// WithUserAgent overrides the User-Agent sent to the met.no api. Defaults to siteName.
+func WithUserAgent(userAgent string) Option {
+	return func(s *MetNoService) {
+		s.userAgent = userAgent
+	}
 }
 
-func getDataFromCaches[T any](service *MetNoService, caches []Cache[T], cacheName string) (*T, cacheInfo, error) {
-	// Prepare variables to store the newest result from any of the caches
-	var newestObj *T
-	var newestInfo cacheInfo
-	var newestIndex int
-	// Prepare a map with the last modified date for each processed cache
-	cacheLastModified := map[int]time.Time{}
-	// Loop thru the caches
-	for i, cache := range caches {
-		// Try get the objects from this cache
-		obj, info, err := cache.GetCache(cacheName)
-		if err != nil {
-			return nil, cacheInfo{}, err
-		}
-		if obj == nil {
-			// Object not cached, continue with next cache
-			service.logger.Debug(fmt.Sprintf("No data in cache %d", i))
-			continue
-		}
-
-		// Store the data if it is the newest of all caches (or the first that has data)
-		if newestObj == nil || newestInfo.LastModified.Before(info.LastModified) {
-			newestObj = obj
-			newestInfo = info
-			newestIndex = i
-		}
-
-		// If the object is not expired, stop processing caches
-		if !isExpired(info.Expires) {
-			service.logger.Debug(fmt.Sprintf("Data in cache %d is not expired, using it", i))
-			break
-		}
-		service.logger.Debug(fmt.Sprintf("Data in cache %d is expired, trying next cache", i))
-
-		// Store the last modified date of this cache
-		cacheLastModified[i] = info.LastModified
+// WithCachePolicy applies a CachePolicy to the default MemoryCache and DiskCache of every
+// met.no product. It has no effect on a product whose cache chain was replaced via WithCaches.
+func WithCachePolicy(policy CachePolicy) Option {
+	return func(s *MetNoService) {
+		s.cachePolicy = policy
 	}
+}
 
-	// No data in all caches found
-	if newestObj == nil {
-		service.logger.Debug("No data in all caches")
-		return nil, cacheInfo{}, nil
+// WithDefaultTTL sets the freshness duration used when an api response has neither a
+// Cache-Control max-age nor an Expires header.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(s *MetNoService) {
+		s.defaultTTL = ttl
 	}
+}
 
-	// If the higher-rated caches had no or an older result, update it
-	for i := 0; i < newestIndex; i++ {
-		prevCacheModified, ok := cacheLastModified[i]
-		if !ok || prevCacheModified.Before(newestInfo.LastModified) {
-			service.logger.Debug(fmt.Sprintf("Update data in cache %d from cache %d", i, newestIndex))
-			if err := caches[i].SetCache(cacheName, newestObj, newestInfo); err != nil {
-				return nil, cacheInfo{}, nil
-			}
-		}
+// WithStaleWhileRevalidate makes the service serve expired cache data (with a warning
+// logged) instead of an error whenever refreshing it from the api fails, e.g. because the
+// network is down.
+func WithStaleWhileRevalidate() Option {
+	return func(s *MetNoService) {
+		s.staleWhileRevalidate = true
 	}
-
-	// Return the data
-	return newestObj, newestInfo, nil
 }
 
-func loadDataFromApi[T interface{}](service *MetNoService, url string, lastCachedData *T, lastCacheInfo cacheInfo) (*T, cacheInfo, error) {
-	service.logger.Debug(fmt.Sprintf("Loading data from api url: %s", url))
-	// Create the request
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
-	if err != nil {
-		return nil, cacheInfo{}, err
-	}
-	req.Header.Set("User-Agent", service.siteName)
-	// Add last modified if we have the info and cached data
-	if !lastCacheInfo.LastModified.IsZero() && lastCachedData != nil {
-		gmtTimeLoc := time.FixedZone("GMT", 0)
-		ifModifiedDate := lastCacheInfo.LastModified.In(gmtTimeLoc).Format(time.RFC1123)
-		req.Header.Set("If-Modified-Since", ifModifiedDate)
-		service.logger.Debug(fmt.Sprintf("Adding If-Modified-Since header: %s", ifModifiedDate))
+// Method to create a new service to interact with the met.no api.
+func NewMetNoService(siteName string, opts ...Option) (*MetNoService, error) {
+	if siteName == "" {
+		return nil, fmt.Errorf("siteName must be defined")
 	}
-
-	// Execute the request
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, cacheInfo{}, err
+	service := &MetNoService{
+		siteName:   siteName,
+		httpClient: http.DefaultClient,
+		clock:      time.Now,
 	}
-	defer resp.Body.Close()
-
-	// Get the response headers regarding times
-	expiresValue, ok := resp.Header["Expires"]
-	if !ok {
-		return nil, cacheInfo{}, fmt.Errorf("failed getting the 'Expires' header")
+	for _, opt := range opts {
+		opt(service)
 	}
-	expiresDate, err := time.Parse(time.RFC1123, expiresValue[0])
-	if err != nil {
-		return nil, cacheInfo{}, fmt.Errorf("failed parsing the expires date: %w", err)
+	if service.logger == nil {
+		service.logger = slog.New(discardHandler{})
 	}
-	lastModifiedValue, ok := resp.Header["Last-Modified"]
-	if !ok {
-		return nil, cacheInfo{}, fmt.Errorf("failed getting the 'Last-Modified' header")
+	if service.userAgent == "" {
+		service.userAgent = siteName
 	}
-	lastModifiedDate, err := time.Parse(time.RFC1123, lastModifiedValue[0])
-	if err != nil {
-		return nil, cacheInfo{}, fmt.Errorf("failed parsing the last-modified date: %w", err)
+	if service.locationForecastCaches == nil {
+		// Caches should be ordered from most to least volatile (or performant)
+		service.locationForecastCaches = []Cache[LocationforecastResult]{
+			&MemoryCache[LocationforecastResult]{Policy: service.cachePolicy, Clock: service.clock},
+			&DiskCache[LocationforecastResult]{CacheDirectory: service.cacheDir, Policy: service.cachePolicy, Clock: service.clock},
+		}
 	}
+	service.locationForecastEndpoint = &endpoint[LocationforecastResult]{caches: service.locationForecastCaches}
+	service.nowcastEndpoint = newEndpoint[NowcastResult](service)
+	service.sunriseEndpoint = newEndpoint[SunriseResult](service)
+	service.airQualityForecastEndpoint = newEndpoint[AirQualityForecastResult](service)
+	service.metAlertsEndpoint = newEndpoint[MetAlertsResult](service)
+	return service, nil
+}
 
-	// Check if the response was 304 - Not Modified
-	if resp.StatusCode == 304 {
-		service.logger.Debug("Data from api not modified")
-		// Return the last data but update the cache info
-		return lastCachedData, cacheInfo{Expires: expiresDate, LastModified: lastModifiedDate}, nil
+// Get a locationforecast result.
+func (s *MetNoService) Locationforecast(lat float64, lon float64, alt int) (*Result[LocationforecastResult], error) {
+	cacheName := s.buildLocationforecastCacheName(lat, lon, alt)
+	if s.prefetcher != nil {
+		s.prefetcher.recordHit(cacheName, lat, lon, alt)
 	}
+	url := fmt.Sprintf("https://api.met.no/weatherapi/locationforecast/2.0/complete?lat=%.4f&lon=%.4f&altitude=%d", lat, lon, alt)
+	return s.locationForecastEndpoint.fetch(s, cacheName, url)
+}
 
-	// Check if the status code is a success code
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		// Read and convert the body
-		var dataObject T
-		if err := json.NewDecoder(resp.Body).Decode(&dataObject); err != nil {
-			return nil, cacheInfo{}, fmt.Errorf("error converting the response body to json: %w", err)
-		}
+func (s *MetNoService) buildLocationforecastCacheName(lat float64, lon float64, alt int) string {
+	return fmt.Sprintf("locationforecast-%.4f-%.4f-%d", lat, lon, alt)
+}
 
-		// Return the values
-		return &dataObject, cacheInfo{Expires: expiresDate, LastModified: lastModifiedDate}, nil
-	}
+// Get a nowcast result, met.no's short-term (0-2.5h) high-resolution forecast.
+func (s *MetNoService) Nowcast(lat float64, lon float64) (*Result[NowcastResult], error) {
+	cacheName := fmt.Sprintf("nowcast-%.4f-%.4f", lat, lon)
+	url := fmt.Sprintf("https://api.met.no/weatherapi/nowcast/2.0/complete?lat=%.4f&lon=%.4f", lat, lon)
+	return s.nowcastEndpoint.fetch(s, cacheName, url)
+}
+
+// Get the sunrise, sunset and related solar times for a location on a given date.
+func (s *MetNoService) Sunrise(lat float64, lon float64, date time.Time) (*Result[SunriseResult], error) {
+	dateString := date.Format("2006-01-02")
+	cacheName := fmt.Sprintf("sunrise-%.4f-%.4f-%s", lat, lon, dateString)
+	url := fmt.Sprintf("https://api.met.no/weatherapi/sunrise/3.0/sun?lat=%.4f&lon=%.4f&date=%s", lat, lon, dateString)
+	return s.sunriseEndpoint.fetch(s, cacheName, url)
+}
+
+// Get an air quality forecast result for a location.
+func (s *MetNoService) AirQualityForecast(lat float64, lon float64) (*Result[AirQualityForecastResult], error) {
+	cacheName := fmt.Sprintf("airqualityforecast-%.4f-%.4f", lat, lon)
+	url := fmt.Sprintf("https://api.met.no/weatherapi/airqualityforecast/0.1/?lat=%.4f&lon=%.4f", lat, lon)
+	return s.airQualityForecastEndpoint.fetch(s, cacheName, url)
+}
+
+// Get the currently active met.no weather alerts (MetAlerts) for a country.
+func (s *MetNoService) MetAlerts(countryCode string) (*Result[MetAlertsResult], error) {
+	cacheName := fmt.Sprintf("metalerts-%s", countryCode)
+	url := fmt.Sprintf("https://api.met.no/weatherapi/metalerts/2.0/current.json?country=%s", countryCode)
+	return s.metAlertsEndpoint.fetch(s, cacheName, url)
+}
+
+func (s *MetNoService) isExpired(checkDate time.Time) bool {
+	return s.clock().After(checkDate)
+}
+
+// SetOffline switches the service between its normal mode and a serve-from-cache-only mode,
+// useful for devices (e.g. e-paper displays) that are only intermittently connected. While
+// offline, every product returns whatever is in its cache, however stale, without calling
+// the api, and errors only if nothing has ever been cached for the request.
+func (s *MetNoService) SetOffline(offline bool) {
+	s.offline.Store(offline)
+}
 
-	// Failed status code
-	return nil, cacheInfo{}, fmt.Errorf("failed getting new data from the api with code: %d", resp.StatusCode)
+func (s *MetNoService) isOffline() bool {
+	return s.offline.Load()
 }
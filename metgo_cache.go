@@ -1,11 +1,15 @@
 package metgo
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 )
 
 type Cache[T any] interface {
@@ -14,41 +18,89 @@ type Cache[T any] interface {
 	ClearCache(cacheName string) error
 }
 
+// ErrBitrot is returned by a cache's GetCache method when a persisted entry's
+// checksum no longer matches its content, meaning the stored data was
+// corrupted after it was written. Callers should treat this like a cache miss
+// and evict the offending entry.
+var ErrBitrot = errors.New("cached data failed its checksum verification")
+
 ////////////////////////////////////////////////////////////
 // Memory Cache
 ////////////////////////////////////////////////////////////
 
+// MemoryCache is safe for concurrent use: its maps are guarded by mutex, which is required
+// since locationforecast lookups for different keys are coalesced independently (see
+// endpoint.fetch) and EnablePrefetch revalidates entries from a background goroutine, both of
+// which run concurrently with foreground GetCache/SetCache calls.
 type MemoryCache[T any] struct {
 	CacheObject     map[string]*T
 	CacheInfoObject map[string]cacheInfo
+	// Policy bounds how many entries are kept in memory. A zero-value Policy imposes no limits.
+	Policy CachePolicy
+	// Clock overrides how TTLOverride computes its Expires time, mainly useful for tests.
+	// Defaults to time.Now.
+	Clock func() time.Time
+
+	mutex   sync.RWMutex
+	tracker cacheTracker
 }
 
 func (m *MemoryCache[T]) GetCache(cacheName string) (*T, cacheInfo, error) {
 	m.makeSureMapIsInitialized()
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	m.tracker.recordAccess(cacheName)
 	return m.CacheObject[cacheName], m.CacheInfoObject[cacheName], nil
 }
 
 func (m *MemoryCache[T]) SetCache(cacheName string, cacheObject *T, cacheInfoObject cacheInfo) error {
 	m.makeSureMapIsInitialized()
+	cacheBytes, err := json.Marshal(cacheObject)
+	if err != nil {
+		return fmt.Errorf("failed converting the data object to json")
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.tracker.shouldSkipCaching(cacheName) {
+		return nil
+	}
+	if m.Policy.TTLOverride > 0 {
+		cacheInfoObject.Expires = m.Clock().Add(m.Policy.TTLOverride)
+	}
 	m.CacheObject[cacheName] = cacheObject
 	m.CacheInfoObject[cacheName] = cacheInfoObject
+	for _, evicted := range m.tracker.recordSet(cacheName, int64(len(cacheBytes))) {
+		delete(m.CacheObject, evicted)
+		delete(m.CacheInfoObject, evicted)
+	}
 	return nil
 }
 
 func (m *MemoryCache[T]) ClearCache(cacheName string) error {
 	m.makeSureMapIsInitialized()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	delete(m.CacheObject, cacheName)
 	delete(m.CacheInfoObject, cacheName)
+	m.tracker.forget(cacheName)
 	return nil
 }
 
 func (m *MemoryCache[T]) makeSureMapIsInitialized() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	if m.CacheObject == nil {
 		m.CacheObject = map[string]*T{}
 	}
 	if m.CacheInfoObject == nil {
 		m.CacheInfoObject = map[string]cacheInfo{}
 	}
+	if m.Clock == nil {
+		m.Clock = time.Now
+	}
+	m.tracker.policy = m.Policy
+	m.tracker.clock = m.Clock
 }
 
 ////////////////////////////////////////////////////////////
@@ -57,6 +109,13 @@ func (m *MemoryCache[T]) makeSureMapIsInitialized() {
 
 type DiskCache[T any] struct {
 	CacheDirectory string
+	// Policy bounds how much disk space and how many entries are kept. A zero-value Policy imposes no limits.
+	Policy CachePolicy
+	// Clock overrides how TTLOverride computes its Expires time, mainly useful for tests.
+	// Defaults to time.Now.
+	Clock func() time.Time
+
+	tracker cacheTracker
 }
 
 func (m *DiskCache[T]) GetCache(cacheName string) (*T, cacheInfo, error) {
@@ -64,6 +123,12 @@ func (m *DiskCache[T]) GetCache(cacheName string) (*T, cacheInfo, error) {
 	if m.CacheDirectory == "" {
 		return nil, cacheInfo{}, nil
 	}
+	if m.Clock == nil {
+		m.Clock = time.Now
+	}
+	m.tracker.policy = m.Policy
+	m.tracker.clock = m.Clock
+	m.tracker.recordAccess(cacheName)
 
 	// Get the file names
 	cacheFileName, cacheInfoFileName := m.getCacheFileNames(cacheName)
@@ -86,6 +151,17 @@ func (m *DiskCache[T]) GetCache(cacheName string) (*T, cacheInfo, error) {
 		return nil, cacheInfo{}, nil
 	}
 
+	// Verify the checksum, if one was stored, to detect bitrot on disk
+	if cacheInfoObject.Checksum != "" {
+		cacheFileBytes, err := os.ReadFile(cacheFilePath)
+		if err != nil {
+			return nil, cacheInfo{}, fmt.Errorf("failed reading the cache file to verify its checksum: %w", err)
+		}
+		if checksum(cacheFileBytes) != cacheInfoObject.Checksum {
+			return nil, cacheInfo{}, ErrBitrot
+		}
+	}
+
 	// Return the values
 	return cacheDataObject, *cacheInfoObject, nil
 }
@@ -95,6 +171,17 @@ func (m *DiskCache[T]) SetCache(cacheName string, cacheObject *T, cacheInfoObjec
 	if m.CacheDirectory == "" {
 		return nil
 	}
+	if m.Clock == nil {
+		m.Clock = time.Now
+	}
+	m.tracker.policy = m.Policy
+	m.tracker.clock = m.Clock
+	if m.tracker.shouldSkipCaching(cacheName) {
+		return nil
+	}
+	if m.Policy.TTLOverride > 0 {
+		cacheInfoObject.Expires = m.Clock().Add(m.Policy.TTLOverride)
+	}
 
 	// Make sure the cache folder exists
 	if err := os.MkdirAll(m.CacheDirectory, os.ModePerm); err != nil {
@@ -114,7 +201,8 @@ func (m *DiskCache[T]) SetCache(cacheName string, cacheObject *T, cacheInfoObjec
 		return fmt.Errorf("failed storing the cache file: %w", err)
 	}
 
-	// CacheInfoObject
+	// CacheInfoObject, with a checksum of the data file so GetCache can detect bitrot
+	cacheInfoObject.Checksum = checksum(cacheString)
 	cacheInfoFilePath := filepath.Join(m.CacheDirectory, cacheInfoFileName)
 	cacheInfoString, err := json.MarshalIndent(cacheInfoObject, "", " ")
 	if err != nil {
@@ -124,6 +212,11 @@ func (m *DiskCache[T]) SetCache(cacheName string, cacheObject *T, cacheInfoObjec
 		return fmt.Errorf("failed storing the info file: %w", err)
 	}
 
+	// Evict least-recently-used entries that no longer fit the policy
+	for _, evicted := range m.tracker.recordSet(cacheName, int64(len(cacheString)+len(cacheInfoString))) {
+		m.ClearCache(evicted)
+	}
+
 	return nil
 }
 
@@ -133,6 +226,7 @@ func (m *DiskCache[T]) ClearCache(cacheName string) error {
 	cacheInfoFilePath := filepath.Join(m.CacheDirectory, cacheInfoFileName)
 	os.Remove(cacheFilePath)
 	os.Remove(cacheInfoFilePath)
+	m.tracker.forget(cacheName)
 	return nil
 }
 
@@ -142,6 +236,13 @@ func (m *DiskCache[T]) getCacheFileNames(cacheName string) (string, string) {
 	return cacheFileName, cacheInfoFileName
 }
 
+// checksum returns a hex-encoded SHA-256 hash of data, used by DiskCache to
+// bitrot-protect the cache entries it persists.
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 func readJsonFromFile[T interface{}](filePath string, errorOnNotFound bool) (*T, error) {
 	fileDescriptor, err := os.Open(filePath)
 	if errors.Is(err, os.ErrNotExist) {
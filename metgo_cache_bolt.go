@@ -0,0 +1,134 @@
+package metgo
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+////////////////////////////////////////////////////////////
+// Bolt Cache
+////////////////////////////////////////////////////////////
+
+// BoltCache is a Cache backed by a single BoltDB file, useful for embedded
+// use cases where a full directory of JSON files (as used by DiskCache) is
+// undesirable.
+type BoltCache[T any] struct {
+	// DBPath is the path to the BoltDB file to use.
+	DBPath string
+	// BucketName is the bucket the entries are stored in. Defaults to "metgo" if empty.
+	BucketName string
+
+	once sync.Once
+	db   *bolt.DB
+	err  error
+}
+
+func (m *BoltCache[T]) GetCache(cacheName string) (*T, cacheInfo, error) {
+	db, err := m.ensureOpen()
+	if err != nil {
+		return nil, cacheInfo{}, err
+	}
+
+	var cacheDataObject *T
+	var cacheInfoObject cacheInfo
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(m.bucketName()))
+		if bucket == nil {
+			return nil
+		}
+		if dataBytes := bucket.Get(m.dataKey(cacheName)); dataBytes != nil {
+			var dataObject T
+			if err := json.Unmarshal(dataBytes, &dataObject); err != nil {
+				return fmt.Errorf("error converting the bolt entry to json: %w", err)
+			}
+			cacheDataObject = &dataObject
+		}
+		if infoBytes := bucket.Get(m.infoKey(cacheName)); infoBytes != nil {
+			if err := json.Unmarshal(infoBytes, &cacheInfoObject); err != nil {
+				return fmt.Errorf("error converting the bolt info entry to json: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, cacheInfo{}, err
+	}
+	if cacheDataObject == nil {
+		return nil, cacheInfo{}, nil
+	}
+	return cacheDataObject, cacheInfoObject, nil
+}
+
+func (m *BoltCache[T]) SetCache(cacheName string, cacheObject *T, cacheInfoObject cacheInfo) error {
+	db, err := m.ensureOpen()
+	if err != nil {
+		return err
+	}
+
+	dataBytes, err := json.Marshal(cacheObject)
+	if err != nil {
+		return fmt.Errorf("failed converting the data object to json")
+	}
+	infoBytes, err := json.Marshal(cacheInfoObject)
+	if err != nil {
+		return fmt.Errorf("failed converting the info object to json")
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(m.bucketName()))
+		if err != nil {
+			return fmt.Errorf("failed creating the bolt bucket: %w", err)
+		}
+		if err := bucket.Put(m.dataKey(cacheName), dataBytes); err != nil {
+			return fmt.Errorf("failed storing the bolt entry: %w", err)
+		}
+		if err := bucket.Put(m.infoKey(cacheName), infoBytes); err != nil {
+			return fmt.Errorf("failed storing the bolt info entry: %w", err)
+		}
+		return nil
+	})
+}
+
+func (m *BoltCache[T]) ClearCache(cacheName string) error {
+	db, err := m.ensureOpen()
+	if err != nil {
+		return err
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(m.bucketName()))
+		if bucket == nil {
+			return nil
+		}
+		bucket.Delete(m.dataKey(cacheName))
+		bucket.Delete(m.infoKey(cacheName))
+		return nil
+	})
+}
+
+func (m *BoltCache[T]) ensureOpen() (*bolt.DB, error) {
+	m.once.Do(func() {
+		m.db, m.err = bolt.Open(m.DBPath, 0600, nil)
+		if m.err != nil {
+			m.err = fmt.Errorf("failed opening the bolt database '%s': %w", m.DBPath, m.err)
+		}
+	})
+	return m.db, m.err
+}
+
+func (m *BoltCache[T]) bucketName() string {
+	if m.BucketName == "" {
+		return "metgo"
+	}
+	return m.BucketName
+}
+
+func (m *BoltCache[T]) dataKey(cacheName string) []byte {
+	return []byte(fmt.Sprintf("%s-data", cacheName))
+}
+
+func (m *BoltCache[T]) infoKey(cacheName string) []byte {
+	return []byte(fmt.Sprintf("%s-info", cacheName))
+}
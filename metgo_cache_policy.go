@@ -0,0 +1,138 @@
+package metgo
+
+import (
+	"sync"
+	"time"
+)
+
+// CachePolicy bounds the resources a cache is allowed to use and lets
+// rarely-requested entries skip caching entirely. It is applied by
+// MemoryCache and DiskCache; a zero-value CachePolicy imposes no limits.
+type CachePolicy struct {
+	// MaxEntries evicts the least-recently-used entry once exceeded. Zero means unlimited.
+	MaxEntries int
+	// MaxBytes evicts least-recently-used entries until the total tracked size fits. Zero means unlimited.
+	MaxBytes int64
+	// Quota caps the number of entries in the same way as MaxEntries, for callers that
+	// share a single cache instance between multiple independent consumers. Zero means unlimited.
+	Quota int
+	// MinAccessesBeforeCache skips storing an entry until it has been looked up at least
+	// this many times, so one-off lookups don't evict data that is actually reused.
+	MinAccessesBeforeCache int
+	// TTLOverride, if non-zero, replaces the Expires time reported by the API with
+	// now+TTLOverride when an entry is stored.
+	TTLOverride time.Duration
+}
+
+// cacheTracker keeps the bookkeeping a CachePolicy needs (access counts and
+// sizes) and decides which entries to evict. It is embedded by the in-process
+// caches; it is safe for concurrent use.
+type cacheTracker struct {
+	policy CachePolicy
+	// clock overrides how lastAccess timestamps (and so LRU eviction order) are computed,
+	// mainly useful for tests. Defaults to time.Now.
+	clock func() time.Time
+
+	mutex       sync.Mutex
+	accessCount map[string]int
+	lastAccess  map[string]time.Time
+	size        map[string]int64
+}
+
+func (t *cacheTracker) recordAccess(cacheName string) int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.ensureInitialized()
+	t.accessCount[cacheName]++
+	t.lastAccess[cacheName] = t.clock()
+	return t.accessCount[cacheName]
+}
+
+// shouldSkipCaching reports whether cacheName hasn't yet been accessed often
+// enough to be worth caching, per policy.MinAccessesBeforeCache.
+func (t *cacheTracker) shouldSkipCaching(cacheName string) bool {
+	if t.policy.MinAccessesBeforeCache <= 0 {
+		return false
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.ensureInitialized()
+	return t.accessCount[cacheName] < t.policy.MinAccessesBeforeCache
+}
+
+// recordSet registers cacheName's size and returns the names of entries that
+// should be evicted to bring the cache back within the policy's limits.
+func (t *cacheTracker) recordSet(cacheName string, sizeBytes int64) []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.ensureInitialized()
+	t.size[cacheName] = sizeBytes
+	t.lastAccess[cacheName] = t.clock()
+	return t.entriesToEvictLocked()
+}
+
+func (t *cacheTracker) forget(cacheName string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.ensureInitialized()
+	delete(t.accessCount, cacheName)
+	delete(t.lastAccess, cacheName)
+	delete(t.size, cacheName)
+}
+
+func (t *cacheTracker) entriesToEvictLocked() []string {
+	maxEntries := t.policy.MaxEntries
+	if t.policy.Quota > 0 && (maxEntries == 0 || t.policy.Quota < maxEntries) {
+		maxEntries = t.policy.Quota
+	}
+
+	var evicted []string
+	for (maxEntries > 0 && len(t.size) > maxEntries) || (t.policy.MaxBytes > 0 && t.totalBytesLocked() > t.policy.MaxBytes) {
+		lruName, found := t.leastRecentlyUsedLocked()
+		if !found {
+			break
+		}
+		delete(t.accessCount, lruName)
+		delete(t.lastAccess, lruName)
+		delete(t.size, lruName)
+		evicted = append(evicted, lruName)
+	}
+	return evicted
+}
+
+func (t *cacheTracker) totalBytesLocked() int64 {
+	var total int64
+	for _, s := range t.size {
+		total += s
+	}
+	return total
+}
+
+func (t *cacheTracker) leastRecentlyUsedLocked() (string, bool) {
+	var lruName string
+	var lruTime time.Time
+	found := false
+	for name, accessed := range t.lastAccess {
+		if !found || accessed.Before(lruTime) {
+			lruName = name
+			lruTime = accessed
+			found = true
+		}
+	}
+	return lruName, found
+}
+
+func (t *cacheTracker) ensureInitialized() {
+	if t.accessCount == nil {
+		t.accessCount = map[string]int{}
+	}
+	if t.lastAccess == nil {
+		t.lastAccess = map[string]time.Time{}
+	}
+	if t.size == nil {
+		t.size = map[string]int64{}
+	}
+	if t.clock == nil {
+		t.clock = time.Now
+	}
+}
@@ -0,0 +1,90 @@
+package metgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+////////////////////////////////////////////////////////////
+// Redis Cache
+////////////////////////////////////////////////////////////
+
+// RedisCache is a Cache backed by a shared Redis instance, useful when
+// multiple instances of a service should share the same cached data.
+// Entries are keyed by SiteName and CacheName so unrelated deployments can
+// safely use the same Redis database.
+type RedisCache[T any] struct {
+	Client    *redis.Client
+	SiteName  string
+	CacheName string
+}
+
+func (m *RedisCache[T]) GetCache(cacheName string) (*T, cacheInfo, error) {
+	ctx := context.Background()
+
+	dataString, err := m.Client.Get(ctx, m.dataKey(cacheName)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, cacheInfo{}, nil
+	} else if err != nil {
+		return nil, cacheInfo{}, fmt.Errorf("failed getting the redis entry: %w", err)
+	}
+	var cacheDataObject T
+	if err := json.Unmarshal([]byte(dataString), &cacheDataObject); err != nil {
+		return nil, cacheInfo{}, fmt.Errorf("error converting the redis entry to json: %w", err)
+	}
+
+	infoString, err := m.Client.Get(ctx, m.infoKey(cacheName)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, cacheInfo{}, nil
+	} else if err != nil {
+		return nil, cacheInfo{}, fmt.Errorf("failed getting the redis info entry: %w", err)
+	}
+	var cacheInfoObject cacheInfo
+	if err := json.Unmarshal([]byte(infoString), &cacheInfoObject); err != nil {
+		return nil, cacheInfo{}, fmt.Errorf("error converting the redis info entry to json: %w", err)
+	}
+
+	return &cacheDataObject, cacheInfoObject, nil
+}
+
+func (m *RedisCache[T]) SetCache(cacheName string, cacheObject *T, cacheInfoObject cacheInfo) error {
+	ctx := context.Background()
+
+	dataBytes, err := json.Marshal(cacheObject)
+	if err != nil {
+		return fmt.Errorf("failed converting the data object to json")
+	}
+	if err := m.Client.Set(ctx, m.dataKey(cacheName), dataBytes, 0).Err(); err != nil {
+		return fmt.Errorf("failed storing the redis entry: %w", err)
+	}
+
+	infoBytes, err := json.Marshal(cacheInfoObject)
+	if err != nil {
+		return fmt.Errorf("failed converting the info object to json")
+	}
+	if err := m.Client.Set(ctx, m.infoKey(cacheName), infoBytes, 0).Err(); err != nil {
+		return fmt.Errorf("failed storing the redis info entry: %w", err)
+	}
+
+	return nil
+}
+
+func (m *RedisCache[T]) ClearCache(cacheName string) error {
+	ctx := context.Background()
+	if err := m.Client.Del(ctx, m.dataKey(cacheName), m.infoKey(cacheName)).Err(); err != nil {
+		return fmt.Errorf("failed clearing the redis entry: %w", err)
+	}
+	return nil
+}
+
+func (m *RedisCache[T]) dataKey(cacheName string) string {
+	return fmt.Sprintf("%s:%s:%s", m.SiteName, m.CacheName, cacheName)
+}
+
+func (m *RedisCache[T]) infoKey(cacheName string) string {
+	return fmt.Sprintf("%s:%s:%s:info", m.SiteName, m.CacheName, cacheName)
+}
@@ -0,0 +1,131 @@
+package metgo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+////////////////////////////////////////////////////////////
+// S3 Cache
+////////////////////////////////////////////////////////////
+
+// S3Cache is a Cache backed by an S3-compatible object bucket. It stores the
+// cached data and its info object as separate objects, which is useful for
+// sharing a cache across many instances without operating a dedicated cache
+// service.
+type S3Cache[T any] struct {
+	Client *s3.Client
+	Bucket string
+	// Prefix is prepended to every object key, e.g. "metgo/".
+	Prefix string
+}
+
+func (m *S3Cache[T]) GetCache(cacheName string) (*T, cacheInfo, error) {
+	ctx := context.Background()
+
+	dataBytes, err := m.getObject(ctx, m.dataKey(cacheName))
+	if err != nil {
+		return nil, cacheInfo{}, err
+	} else if dataBytes == nil {
+		return nil, cacheInfo{}, nil
+	}
+	var cacheDataObject T
+	if err := json.Unmarshal(dataBytes, &cacheDataObject); err != nil {
+		return nil, cacheInfo{}, fmt.Errorf("error converting the s3 object to json: %w", err)
+	}
+
+	infoBytes, err := m.getObject(ctx, m.infoKey(cacheName))
+	if err != nil {
+		return nil, cacheInfo{}, err
+	} else if infoBytes == nil {
+		return nil, cacheInfo{}, nil
+	}
+	var cacheInfoObject cacheInfo
+	if err := json.Unmarshal(infoBytes, &cacheInfoObject); err != nil {
+		return nil, cacheInfo{}, fmt.Errorf("error converting the s3 info object to json: %w", err)
+	}
+
+	return &cacheDataObject, cacheInfoObject, nil
+}
+
+func (m *S3Cache[T]) SetCache(cacheName string, cacheObject *T, cacheInfoObject cacheInfo) error {
+	ctx := context.Background()
+
+	dataBytes, err := json.MarshalIndent(cacheObject, "", " ")
+	if err != nil {
+		return fmt.Errorf("failed converting the data object to json")
+	}
+	if err := m.putObject(ctx, m.dataKey(cacheName), dataBytes); err != nil {
+		return err
+	}
+
+	infoBytes, err := json.MarshalIndent(cacheInfoObject, "", " ")
+	if err != nil {
+		return fmt.Errorf("failed converting the info object to json")
+	}
+	return m.putObject(ctx, m.infoKey(cacheName), infoBytes)
+}
+
+func (m *S3Cache[T]) ClearCache(cacheName string) error {
+	ctx := context.Background()
+	_, err := m.Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(m.Bucket),
+		Delete: &types.Delete{
+			Objects: []types.ObjectIdentifier{
+				{Key: aws.String(m.dataKey(cacheName))},
+				{Key: aws.String(m.infoKey(cacheName))},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed deleting the s3 objects: %w", err)
+	}
+	return nil
+}
+
+func (m *S3Cache[T]) getObject(ctx context.Context, key string) ([]byte, error) {
+	output, err := m.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(m.Bucket),
+		Key:    aws.String(key),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed getting the s3 object '%s': %w", key, err)
+	}
+	defer output.Body.Close()
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading the s3 object '%s': %w", key, err)
+	}
+	return data, nil
+}
+
+func (m *S3Cache[T]) putObject(ctx context.Context, key string, data []byte) error {
+	_, err := m.Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(m.Bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return fmt.Errorf("failed storing the s3 object '%s': %w", key, err)
+	}
+	return nil
+}
+
+func (m *S3Cache[T]) dataKey(cacheName string) string {
+	return fmt.Sprintf("%smetno-%s.json", m.Prefix, cacheName)
+}
+
+func (m *S3Cache[T]) infoKey(cacheName string) string {
+	return fmt.Sprintf("%smetno-%s-info.json", m.Prefix, cacheName)
+}
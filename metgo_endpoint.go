@@ -0,0 +1,331 @@
+package metgo
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// endpoint ties together the cache chain and in-flight request deduplication for a single
+// met.no product (locationforecast, nowcast, ...), so adding a new product only requires
+// declaring its url and response type, not reimplementing the cache/fetch plumbing.
+type endpoint[T any] struct {
+	caches []Cache[T]
+	group  singleflight.Group
+}
+
+// fetch returns the cached data for cacheName if it is still fresh, otherwise it loads it
+// from url, coalescing concurrent lookups for the same cacheName into a single api call. The
+// returned Result.Stale is true whenever Data is expired cache data served instead of a
+// successful api call, so callers can tell fresh and stale-while-revalidate/offline data apart.
+func (e *endpoint[T]) fetch(service *MetNoService, cacheName string, url string) (*Result[T], error) {
+	cacheObject, cacheInfoObject, err := getDataFromCaches(service, e.caches, cacheName)
+	if err != nil {
+		return nil, err
+	}
+	// If we have a cache object which is not expired, return it
+	if cacheObject != nil && !service.isExpired(cacheInfoObject.Expires) {
+		service.logger.Debug("Found valid data in cache")
+		return &Result[T]{Data: cacheObject, Age: resultAge(service, cacheInfoObject)}, nil
+	}
+
+	// In offline mode, never call the api: serve whatever is cached, however stale
+	if service.isOffline() {
+		if cacheObject != nil {
+			service.logger.Warn(fmt.Sprintf("Service is offline, serving stale data for %s", cacheName))
+			return &Result[T]{Data: cacheObject, Stale: true, Age: resultAge(service, cacheInfoObject)}, nil
+		}
+		return nil, fmt.Errorf("service is offline and no cached data is available for %q", cacheName)
+	}
+
+	result, err, _ := e.group.Do(cacheName, func() (interface{}, error) {
+		obj, info, err := e.fetchAndStore(service, cacheName, url, cacheObject, cacheInfoObject)
+		if err != nil {
+			if service.staleWhileRevalidate && cacheObject != nil {
+				service.logger.Warn(fmt.Sprintf("Failed refreshing %s, serving stale data: %s", cacheName, err))
+				return &Result[T]{Data: cacheObject, Stale: true, Age: resultAge(service, cacheInfoObject)}, nil
+			}
+			return nil, err
+		}
+		return &Result[T]{Data: obj, Age: resultAge(service, info)}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*Result[T]), nil
+}
+
+// resultAge returns how long ago info.LastModified was, or zero if the api never reported a
+// Last-Modified date for this entry.
+func resultAge(service *MetNoService, info cacheInfo) time.Duration {
+	if info.LastModified.IsZero() {
+		return 0
+	}
+	return service.clock().Sub(info.LastModified)
+}
+
+// fetchAndStore always loads cacheName from url and updates every cache in the chain,
+// regardless of whether the currently cached data is still fresh. It is used directly by
+// the prefetcher, which revalidates entries ahead of their expiry.
+func (e *endpoint[T]) fetchAndStore(service *MetNoService, cacheName string, url string, lastCachedData *T, lastCacheInfo cacheInfo) (*T, cacheInfo, error) {
+	apiCacheObject, apiCacheInfoObject, err := loadDataFromApi(service, url, lastCachedData, lastCacheInfo)
+	if err != nil {
+		return nil, cacheInfo{}, err
+	}
+	service.logger.Debug("Loaded from api")
+
+	for _, cache := range e.caches {
+		if err := cache.SetCache(cacheName, apiCacheObject, apiCacheInfoObject); err != nil {
+			return nil, cacheInfo{}, err
+		}
+	}
+
+	return apiCacheObject, apiCacheInfoObject, nil
+}
+
+// newEndpoint builds the default, tiered cache chain (memory, then disk) for a met.no product.
+func newEndpoint[T any](service *MetNoService) *endpoint[T] {
+	return &endpoint[T]{
+		// Caches should be ordered from most to least volatile (or performant)
+		caches: []Cache[T]{
+			&MemoryCache[T]{Policy: service.cachePolicy, Clock: service.clock},
+			&DiskCache[T]{CacheDirectory: service.cacheDir, Policy: service.cachePolicy, Clock: service.clock},
+		},
+	}
+}
+
+////////////////////////////////////////////////////////////
+// Helper methods
+////////////////////////////////////////////////////////////
+
+func getDataFromCaches[T any](service *MetNoService, caches []Cache[T], cacheName string) (*T, cacheInfo, error) {
+	// Prepare variables to store the newest result from any of the caches
+	var newestObj *T
+	var newestInfo cacheInfo
+	var newestIndex int
+	// Prepare a map with the last modified date for each processed cache
+	cacheLastModified := map[int]time.Time{}
+	// Loop thru the caches
+	for i, cache := range caches {
+		// Try get the objects from this cache
+		obj, info, err := cache.GetCache(cacheName)
+		if err != nil {
+			return nil, cacheInfo{}, err
+		}
+		if obj == nil {
+			// Object not cached, continue with next cache
+			service.logger.Debug(fmt.Sprintf("No data in cache %d", i))
+			continue
+		}
+
+		// Store the data if it is the newest of all caches (or the first that has data)
+		if newestObj == nil || newestInfo.LastModified.Before(info.LastModified) {
+			newestObj = obj
+			newestInfo = info
+			newestIndex = i
+		}
+
+		// If the object is not expired, stop processing caches
+		if !service.isExpired(info.Expires) {
+			service.logger.Debug(fmt.Sprintf("Data in cache %d is not expired, using it", i))
+			break
+		}
+		service.logger.Debug(fmt.Sprintf("Data in cache %d is expired, trying next cache", i))
+
+		// Store the last modified date of this cache
+		cacheLastModified[i] = info.LastModified
+	}
+
+	// No data in all caches found
+	if newestObj == nil {
+		service.logger.Debug("No data in all caches")
+		return nil, cacheInfo{}, nil
+	}
+
+	// If the higher-rated caches had no or an older result, update it
+	for i := 0; i < newestIndex; i++ {
+		prevCacheModified, ok := cacheLastModified[i]
+		if !ok || prevCacheModified.Before(newestInfo.LastModified) {
+			service.logger.Debug(fmt.Sprintf("Update data in cache %d from cache %d", i, newestIndex))
+			if err := caches[i].SetCache(cacheName, newestObj, newestInfo); err != nil {
+				return nil, cacheInfo{}, nil
+			}
+		}
+	}
+
+	// Return the data
+	return newestObj, newestInfo, nil
+}
+
+func loadDataFromApi[T interface{}](service *MetNoService, url string, lastCachedData *T, lastCacheInfo cacheInfo) (*T, cacheInfo, error) {
+	service.logger.Debug(fmt.Sprintf("Loading data from api url: %s", url))
+	// Create the request
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	if err != nil {
+		return nil, cacheInfo{}, err
+	}
+	req.Header.Set("User-Agent", service.userAgent)
+	// Add conditional-request headers if we have the info and cached data
+	if lastCachedData != nil {
+		if !lastCacheInfo.LastModified.IsZero() {
+			gmtTimeLoc := time.FixedZone("GMT", 0)
+			ifModifiedDate := lastCacheInfo.LastModified.In(gmtTimeLoc).Format(time.RFC1123)
+			req.Header.Set("If-Modified-Since", ifModifiedDate)
+			service.logger.Debug(fmt.Sprintf("Adding If-Modified-Since header: %s", ifModifiedDate))
+		}
+		if lastCacheInfo.ETag != "" {
+			req.Header.Set("If-None-Match", lastCacheInfo.ETag)
+		}
+	}
+
+	// Execute the request
+	resp, err := service.httpClient.Do(req)
+	if err != nil {
+		return nil, cacheInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	// Failed status codes are surfaced as a typed APIError so callers can tell a bad
+	// User-Agent (403), rate limiting (429) and server errors (5xx) apart
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, cacheInfo{}, &APIError{StatusCode: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")), Body: body}
+	}
+
+	lastModifiedDate, err := parseOptionalHeaderTime(resp.Header.Get("Last-Modified"))
+	if err != nil {
+		return nil, cacheInfo{}, fmt.Errorf("failed parsing the last-modified date: %w", err)
+	}
+	etag := resp.Header.Get("ETag")
+
+	// Check if the response was 304 - Not Modified. Such a response need not repeat the
+	// original's freshness headers, so fall back to the expiry we already had cached
+	// instead of failing a perfectly successful revalidation.
+	if resp.StatusCode == 304 {
+		service.logger.Debug("Data from api not modified")
+		expiresDate, err := computeExpires(service, resp.Header)
+		if err != nil {
+			expiresDate = lastCacheInfo.Expires
+		}
+		// A 304 need not repeat Last-Modified/ETag either; keep the prior validators so
+		// future requests can still be sent conditionally instead of degrading to
+		// unconditional fetches.
+		lastModifiedDate = firstNonZeroTime(lastModifiedDate, lastCacheInfo.LastModified)
+		etag = firstNonEmpty(etag, lastCacheInfo.ETag)
+		// Return the last data but update the cache info
+		return lastCachedData, cacheInfo{Expires: expiresDate, LastModified: lastModifiedDate, ETag: etag}, nil
+	}
+
+	expiresDate, err := computeExpires(service, resp.Header)
+	if err != nil {
+		return nil, cacheInfo{}, err
+	}
+
+	// Transparently decompress a gzip-encoded body
+	bodyReader := io.Reader(resp.Body)
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, cacheInfo{}, fmt.Errorf("failed decompressing the gzip response body: %w", err)
+		}
+		defer gzipReader.Close()
+		bodyReader = gzipReader
+	}
+
+	// Read and convert the body
+	var dataObject T
+	if err := json.NewDecoder(bodyReader).Decode(&dataObject); err != nil {
+		return nil, cacheInfo{}, fmt.Errorf("error converting the response body to json: %w", err)
+	}
+
+	// Return the values
+	return &dataObject, cacheInfo{Expires: expiresDate, LastModified: lastModifiedDate, ETag: etag}, nil
+}
+
+// computeExpires derives the cache expiry from the response headers: Cache-Control's
+// max-age (relative to the Date header, or now if absent) takes priority, then the
+// Expires header, then the service's configured default TTL.
+func computeExpires(service *MetNoService, header http.Header) (time.Time, error) {
+	if maxAge, ok := parseMaxAge(header.Get("Cache-Control")); ok {
+		base := service.clock()
+		if dateHeader := header.Get("Date"); dateHeader != "" {
+			if parsedDate, err := http.ParseTime(dateHeader); err == nil {
+				base = parsedDate
+			}
+		}
+		return base.Add(time.Duration(maxAge) * time.Second), nil
+	}
+
+	if expiresHeader := header.Get("Expires"); expiresHeader != "" {
+		expiresDate, err := http.ParseTime(expiresHeader)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed parsing the expires date: %w", err)
+		}
+		return expiresDate, nil
+	}
+
+	if service.defaultTTL > 0 {
+		return service.clock().Add(service.defaultTTL), nil
+	}
+
+	return time.Time{}, fmt.Errorf("response has neither a Cache-Control max-age nor an Expires header, and no default TTL is configured")
+}
+
+// parseMaxAge extracts the max-age directive (in seconds) from a Cache-Control header value.
+func parseMaxAge(cacheControl string) (int, bool) {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(directive), "=")
+		if !found || !strings.EqualFold(strings.TrimSpace(name), "max-age") {
+			continue
+		}
+		maxAge, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		return maxAge, true
+	}
+	return 0, false
+}
+
+// parseOptionalHeaderTime parses a HTTP date header that may be absent, accepting any of the
+// RFC1123, RFC850 or ANSI-C formats used in the wild.
+func parseOptionalHeaderTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return http.ParseTime(value)
+}
+
+// firstNonZeroTime returns value if it is not the zero time, otherwise fallback.
+func firstNonZeroTime(value time.Time, fallback time.Time) time.Time {
+	if value.IsZero() {
+		return fallback
+	}
+	return value
+}
+
+// firstNonEmpty returns value if it is not empty, otherwise fallback.
+func firstNonEmpty(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds. A missing or invalid
+// header results in a zero duration.
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
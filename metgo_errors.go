@@ -0,0 +1,24 @@
+package metgo
+
+import (
+	"fmt"
+	"time"
+)
+
+// APIError is returned by loadDataFromApi when the met.no api responds with a failure status
+// code, so callers can distinguish a bad User-Agent (403), rate limiting (429) and server
+// errors (5xx) from a network or parsing failure.
+type APIError struct {
+	StatusCode int
+	// RetryAfter is the duration from the response's Retry-After header, if present.
+	RetryAfter time.Duration
+	// Body is the (possibly truncated) response body, useful for diagnosing the failure.
+	Body []byte
+}
+
+func (e *APIError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("met.no api responded with status %d, retry after %s: %s", e.StatusCode, e.RetryAfter, e.Body)
+	}
+	return fmt.Sprintf("met.no api responded with status %d: %s", e.StatusCode, e.Body)
+}
@@ -0,0 +1,15 @@
+package metgo
+
+import (
+	"context"
+	"log/slog"
+)
+
+// discardHandler is a slog.Handler that drops every record, used as the default logger
+// when the caller doesn't provide one.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool   { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error  { return nil }
+func (h discardHandler) WithAttrs(attrs []slog.Attr) slog.Handler { return h }
+func (h discardHandler) WithGroup(name string) slog.Handler       { return h }
@@ -0,0 +1,89 @@
+package metgo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// prefetcher records which locationforecast cache entries are actually being requested, so
+// EnablePrefetch can revalidate only the popular ones instead of blindly refreshing everything.
+type prefetcher struct {
+	mutex sync.Mutex
+	keys  map[string]locationforecastParams
+}
+
+type locationforecastParams struct {
+	lat float64
+	lon float64
+	alt int
+}
+
+func (p *prefetcher) recordHit(cacheName string, lat float64, lon float64, alt int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.keys == nil {
+		p.keys = map[string]locationforecastParams{}
+	}
+	p.keys[cacheName] = locationforecastParams{lat: lat, lon: lon, alt: alt}
+}
+
+func (p *prefetcher) snapshot() map[string]locationforecastParams {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	keys := make(map[string]locationforecastParams, len(p.keys))
+	for cacheName, params := range p.keys {
+		keys[cacheName] = params
+	}
+	return keys
+}
+
+// EnablePrefetch starts a background goroutine that, every interval, revalidates the
+// locationforecast entries that have actually been requested through this service and whose
+// cached data is about to expire, using If-Modified-Since so foreground callers keep seeing
+// warm data instead of all hitting the api at once right after expiry. The goroutine stops
+// once ctx is cancelled.
+func (s *MetNoService) EnablePrefetch(ctx context.Context, interval time.Duration) {
+	if s.prefetcher == nil {
+		s.prefetcher = &prefetcher{}
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.revalidateExpiringEntries(interval)
+			}
+		}
+	}()
+}
+
+func (s *MetNoService) revalidateExpiringEntries(interval time.Duration) {
+	// An offline service never calls the api, so there is nothing to revalidate
+	if s.isOffline() {
+		return
+	}
+	for cacheName, params := range s.prefetcher.snapshot() {
+		cacheObject, cacheInfoObject, err := getDataFromCaches(s, s.locationForecastEndpoint.caches, cacheName)
+		if err != nil {
+			s.logger.Warn(fmt.Sprintf("Failed reading cache %s for prefetch: %s", cacheName, err))
+			continue
+		}
+		if cacheObject == nil || cacheInfoObject.Expires.IsZero() {
+			continue
+		}
+		// Only revalidate entries that will expire before the next prefetch tick
+		if s.clock().Add(interval).Before(cacheInfoObject.Expires) {
+			continue
+		}
+		s.logger.Debug(fmt.Sprintf("Prefetching soon-to-expire cache entry %s", cacheName))
+		url := fmt.Sprintf("https://api.met.no/weatherapi/locationforecast/2.0/complete?lat=%.4f&lon=%.4f&altitude=%d", params.lat, params.lon, params.alt)
+		if _, _, err := s.locationForecastEndpoint.fetchAndStore(s, cacheName, url, cacheObject, cacheInfoObject); err != nil {
+			s.logger.Warn(fmt.Sprintf("Failed prefetching %s: %s", cacheName, err))
+		}
+	}
+}
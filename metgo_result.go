@@ -0,0 +1,16 @@
+package metgo
+
+import "time"
+
+// Result wraps a fetched value together with metadata about how it was served, so callers
+// can tell data that was refreshed from the api apart from data served stale because a
+// refresh failed (see WithStaleWhileRevalidate) or because the service is offline (see
+// SetOffline).
+type Result[T any] struct {
+	Data *T
+	// Stale is true when Data is expired cache data served instead of a successful api call.
+	Stale bool
+	// Age is how long ago the api last reported Data as modified (its Last-Modified header),
+	// or zero if the api never reported a Last-Modified date for it.
+	Age time.Duration
+}
@@ -24,7 +24,7 @@ func TestMetNoCache(t *testing.T) {
 
 	// Initialize the service
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
-	service, err := NewMetNoService("https://github.com/Roemer/metgo", cacheDirectory, logger)
+	service, err := NewMetNoService("https://github.com/Roemer/metgo", WithCacheDirectory(cacheDirectory), WithLogger(logger))
 	assert.NoError(err)
 	assert.NotNil(service)
 
@@ -58,3 +58,75 @@ func TestMetNoCache(t *testing.T) {
 	assert.NoError(err)
 	assert.NotNil(locationforecastResult)
 }
+
+func TestMemoryCachePolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := &MemoryCache[string]{Policy: CachePolicy{MaxEntries: 2, MinAccessesBeforeCache: 2}}
+
+	// First access of "a" happens before it is ever cached, so it should be skipped
+	value := "a-value"
+	cache.GetCache("a")
+	assert.NoError(cache.SetCache("a", &value, cacheInfo{}))
+	obj, _, err := cache.GetCache("a")
+	assert.NoError(err)
+	assert.Nil(obj)
+
+	// Second access meets MinAccessesBeforeCache, so it should be stored
+	assert.NoError(cache.SetCache("a", &value, cacheInfo{}))
+	obj, _, err = cache.GetCache("a")
+	assert.NoError(err)
+	assert.Equal(&value, obj)
+
+	// Adding two more entries should evict "a" (the least-recently-used) once MaxEntries is exceeded
+	cache.GetCache("b")
+	cache.GetCache("b")
+	assert.NoError(cache.SetCache("b", &value, cacheInfo{}))
+	cache.GetCache("c")
+	cache.GetCache("c")
+	assert.NoError(cache.SetCache("c", &value, cacheInfo{}))
+
+	obj, _, err = cache.GetCache("a")
+	assert.NoError(err)
+	assert.Nil(obj)
+}
+
+func TestTimeseriesDerivedHelpers(t *testing.T) {
+	assert := assert.New(t)
+
+	var ts Timeseries
+	ts.Data.Instant.Details.AirTemperature = 20
+	ts.Data.Instant.Details.WindFromDirection = 200
+	ts.Data.Instant.Details.WindSpeed = 10
+	ts.Data.Next1_Hours = &NextXHours{}
+	ts.Data.Next1_Hours.Summary.SymbolCode = "partlycloudy_day"
+
+	assert.Equal(68.0, ts.Temperature(Fahrenheit))
+	assert.Equal(36.0, ts.WindSpeed(KilometersPerHour))
+	assert.Equal("SSW", ts.WindDirectionCardinal())
+	assert.Equal(5, ts.BeaufortScale())
+	assert.Equal(20.0, ts.ApparentTemperature())
+	assert.Equal("partlycloudy_day", ts.SymbolCode())
+}
+
+func TestLocationforecastDailySummaries(t *testing.T) {
+	assert := assert.New(t)
+
+	result := &LocationforecastResult{}
+	addEntry := func(hour int, temp float64) {
+		var ts Timeseries
+		ts.Time = time.Date(2024, 1, 1, hour, 0, 0, 0, time.UTC)
+		ts.Data.Instant.Details.AirTemperature = temp
+		result.Properties.Timeseries = append(result.Properties.Timeseries, ts)
+	}
+	addEntry(0, 2)
+	addEntry(12, 8)
+	addEntry(23, 4)
+	addEntry(24, -1) // 2024-01-02 00:00
+
+	summaries := result.DailySummaries(time.UTC)
+	assert.Equal(2, len(summaries))
+	assert.Equal(2.0, summaries[0].TemperatureMin)
+	assert.Equal(8.0, summaries[0].TemperatureMax)
+	assert.Equal(-1.0, summaries[1].TemperatureMin)
+}
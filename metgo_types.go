@@ -2,9 +2,14 @@ package metgo
 
 import "time"
 
-type cacheMetaData struct {
+type cacheInfo struct {
 	Expires      time.Time `json:"expires"`
 	LastModified time.Time `json:"lastModified"`
+	// ETag is the response's ETag header, if any, re-sent as If-None-Match on the next request.
+	ETag string `json:"etag,omitempty"`
+	// Checksum is a hex-encoded hash of the cached data, written and verified by
+	// DiskCache to detect bitrot. The other caches neither set nor check it.
+	Checksum string `json:"checksum,omitempty"`
 }
 
 type LocationforecastResult struct {
@@ -82,3 +87,102 @@ type NextXHours struct {
 		UltravioletIndexClearSkyMax float64 `json:"ultraviolet_index_clear_sky_max"`
 	} `json:"details"`
 }
+
+// NowcastResult is the response of the nowcast product, met.no's high-resolution
+// 0-2.5h forecast. It shares the locationforecast's GeoJSON shape.
+type NowcastResult struct {
+	Type string `json:"type"`
+
+	Geometry struct {
+		Type        string    `json:"type"`
+		Coordinates []float64 `json:"coordinates"`
+	} `json:"geometry"`
+
+	Properties struct {
+		Meta       Meta         `json:"meta"`
+		Timeseries []Timeseries `json:"timeseries"`
+	} `json:"properties"`
+}
+
+// SunriseResult is the response of the sunrise product for a single date.
+type SunriseResult struct {
+	Copyright string `json:"copyright"`
+	Type      string `json:"type"`
+	Geometry  struct {
+		Type        string    `json:"type"`
+		Coordinates []float64 `json:"coordinates"`
+	} `json:"geometry"`
+	When struct {
+		Interval []time.Time `json:"interval"`
+	} `json:"when"`
+	Properties struct {
+		Body          string   `json:"body"`
+		Sunrise       SunEvent `json:"sunrise"`
+		Sunset        SunEvent `json:"sunset"`
+		Solarnoon     SunEvent `json:"solarnoon"`
+		Solarmidnight SunEvent `json:"solarmidnight"`
+	} `json:"properties"`
+}
+
+// SunEvent describes the time and azimuth/elevation of a single solar event.
+type SunEvent struct {
+	Time      time.Time `json:"time"`
+	Azimuth   float64   `json:"azimuth"`
+	Elevation float64   `json:"elevation,omitempty"`
+	Desc      string    `json:"desc,omitempty"`
+}
+
+// AirQualityForecastResult is the response of the airqualityforecast product.
+type AirQualityForecastResult struct {
+	Type string `json:"type"`
+
+	Geometry struct {
+		Type        string    `json:"type"`
+		Coordinates []float64 `json:"coordinates"`
+	} `json:"geometry"`
+
+	Properties struct {
+		Meta struct {
+			UpdatedAt time.Time         `json:"updated_at"`
+			Units     map[string]string `json:"units"`
+		} `json:"meta"`
+		Timeseries []AirQualityTimeseries `json:"timeseries"`
+	} `json:"properties"`
+}
+
+type AirQualityTimeseries struct {
+	Time time.Time `json:"time"`
+	Data struct {
+		Instant struct {
+			Details struct {
+				AQI               float64 `json:"aqi"`
+				PM10Concentration float64 `json:"pm10_concentration"`
+				PM25Concentration float64 `json:"pm25_concentration"`
+				NO2Concentration  float64 `json:"no2_concentration"`
+				O3Concentration   float64 `json:"o3_concentration"`
+			} `json:"details"`
+		} `json:"instant"`
+	} `json:"data"`
+}
+
+// MetAlertsResult is the response of the metalerts product for a country.
+type MetAlertsResult struct {
+	Type     string `json:"type"`
+	Features []struct {
+		Type       string `json:"type"`
+		Geometry   any    `json:"geometry"`
+		Properties struct {
+			Area               string    `json:"area"`
+			AwarenessLevel     string    `json:"awareness_level"`
+			Certainty          string    `json:"certainty"`
+			Description        string    `json:"description"`
+			Event              string    `json:"event"`
+			EventAwarenessName string    `json:"eventAwarenessName"`
+			Instruction        string    `json:"instruction"`
+			Severity           string    `json:"severity"`
+			Title              string    `json:"title"`
+			Onset              time.Time `json:"onset"`
+			Expires            time.Time `json:"expires"`
+		} `json:"properties"`
+	} `json:"features"`
+}
@@ -0,0 +1,199 @@
+package metgo
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// TemperatureUnit is a unit a Timeseries temperature can be converted to.
+type TemperatureUnit int
+
+const (
+	Celsius TemperatureUnit = iota
+	Fahrenheit
+	Kelvin
+)
+
+// SpeedUnit is a unit a Timeseries wind speed can be converted to.
+type SpeedUnit int
+
+const (
+	MetersPerSecond SpeedUnit = iota
+	KilometersPerHour
+	MilesPerHour
+	Knots
+)
+
+// windDirectionNames lists the 16 compass points in 22.5 degree steps, starting at north.
+var windDirectionNames = []string{
+	"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE",
+	"S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW",
+}
+
+// Temperature returns the instant air temperature, converted to unit. The api always
+// reports air_temperature in degrees Celsius.
+func (t Timeseries) Temperature(unit TemperatureUnit) float64 {
+	celsius := t.Data.Instant.Details.AirTemperature
+	switch unit {
+	case Fahrenheit:
+		return celsius*9/5 + 32
+	case Kelvin:
+		return celsius + 273.15
+	default:
+		return celsius
+	}
+}
+
+// WindSpeed returns the instant wind speed, converted to unit. The api always reports
+// wind_speed in meters per second.
+func (t Timeseries) WindSpeed(unit SpeedUnit) float64 {
+	mps := t.Data.Instant.Details.WindSpeed
+	switch unit {
+	case KilometersPerHour:
+		return mps * 3.6
+	case MilesPerHour:
+		return mps * 2.236936
+	case Knots:
+		return mps * 1.943844
+	default:
+		return mps
+	}
+}
+
+// WindDirectionCardinal returns the instant wind_from_direction as a 16-point compass
+// direction such as "NNE" or "SW".
+func (t Timeseries) WindDirectionCardinal() string {
+	degrees := math.Mod(t.Data.Instant.Details.WindFromDirection, 360)
+	if degrees < 0 {
+		degrees += 360
+	}
+	index := int(math.Mod(degrees+11.25, 360) / 22.5)
+	return windDirectionNames[index]
+}
+
+// HeatIndex returns the NWS heat index in degrees Celsius, which factors in humidity on
+// top of the air temperature. Below 27°C it is not meaningful and the air temperature is
+// returned unchanged.
+func (t Timeseries) HeatIndex() float64 {
+	tempC := t.Data.Instant.Details.AirTemperature
+	if tempC < 27 {
+		return tempC
+	}
+	tempF := t.Temperature(Fahrenheit)
+	rh := t.Data.Instant.Details.RelativeHumidity
+	heatIndexF := -42.379 + 2.04901523*tempF + 10.14333127*rh - 0.22475541*tempF*rh -
+		0.00683783*tempF*tempF - 0.05481717*rh*rh + 0.00122874*tempF*tempF*rh +
+		0.00085282*tempF*rh*rh - 0.00000199*tempF*tempF*rh*rh
+	return (heatIndexF - 32) * 5 / 9
+}
+
+// WindChill returns the wind chill in degrees Celsius. It is only meaningful at or below
+// 10°C with a wind speed of at least 4.8 km/h, and the air temperature is returned
+// unchanged outside of that range.
+func (t Timeseries) WindChill() float64 {
+	tempC := t.Data.Instant.Details.AirTemperature
+	windKmh := t.WindSpeed(KilometersPerHour)
+	if tempC > 10 || windKmh < 4.8 {
+		return tempC
+	}
+	return 13.12 + 0.6215*tempC - 11.37*math.Pow(windKmh, 0.16) + 0.3965*tempC*math.Pow(windKmh, 0.16)
+}
+
+// ApparentTemperature returns the wind chill at or below 10°C, the heat index at or above 27°C,
+// or the plain air temperature in between.
+func (t Timeseries) ApparentTemperature() float64 {
+	tempC := t.Data.Instant.Details.AirTemperature
+	switch {
+	case tempC <= 10:
+		return t.WindChill()
+	case tempC >= 27:
+		return t.HeatIndex()
+	default:
+		return tempC
+	}
+}
+
+// beaufortUpperBounds holds the upper wind speed bound (in m/s) of Beaufort scale 0 to 11.
+var beaufortUpperBounds = []float64{0.3, 1.6, 3.4, 5.5, 8.0, 10.8, 13.9, 17.2, 20.8, 24.5, 28.5, 32.7}
+
+// BeaufortScale returns the instant wind speed as a Beaufort scale number from 0 to 12.
+func (t Timeseries) BeaufortScale() int {
+	mps := t.Data.Instant.Details.WindSpeed
+	for scale, upperBound := range beaufortUpperBounds {
+		if mps < upperBound {
+			return scale
+		}
+	}
+	return len(beaufortUpperBounds)
+}
+
+// SymbolCode returns the weather symbol from the shortest available summary period
+// (next_1_hours, then next_6_hours, then next_12_hours), or an empty string if none of
+// them are present.
+func (t Timeseries) SymbolCode() string {
+	switch {
+	case t.Data.Next1_Hours != nil:
+		return t.Data.Next1_Hours.Summary.SymbolCode
+	case t.Data.Next6_Hours != nil:
+		return t.Data.Next6_Hours.Summary.SymbolCode
+	case t.Data.Next12_Hours != nil:
+		return t.Data.Next12_Hours.Summary.SymbolCode
+	default:
+		return ""
+	}
+}
+
+// DailySummary is a per-day rollup of a LocationforecastResult's timeseries, as used by
+// renderers (dashboards, e-paper displays) that show a multi-day outlook.
+type DailySummary struct {
+	Date                time.Time
+	TemperatureMin      float64
+	TemperatureMax      float64
+	PrecipitationAmount float64
+	SymbolCode          string
+}
+
+// DailySummaries groups the timeseries by calendar day in loc (the api itself reports
+// every entry in UTC) and returns one DailySummary per day, ordered by date. The
+// precipitation amount is summed from each entry's next_1_hours detail, and the symbol
+// code is taken from the first entry of the day that has one.
+func (r *LocationforecastResult) DailySummaries(loc *time.Location) []DailySummary {
+	if loc == nil {
+		loc = time.UTC
+	}
+	summariesByDate := map[string]*DailySummary{}
+	var order []string
+	for _, ts := range r.Properties.Timeseries {
+		dateKey := ts.Time.In(loc).Format("2006-01-02")
+		summary, ok := summariesByDate[dateKey]
+		if !ok {
+			date, err := time.ParseInLocation("2006-01-02", dateKey, loc)
+			if err != nil {
+				continue
+			}
+			summary = &DailySummary{Date: date, TemperatureMin: ts.Data.Instant.Details.AirTemperature, TemperatureMax: ts.Data.Instant.Details.AirTemperature}
+			summariesByDate[dateKey] = summary
+			order = append(order, dateKey)
+		}
+		if temp := ts.Data.Instant.Details.AirTemperature; temp < summary.TemperatureMin {
+			summary.TemperatureMin = temp
+		} else if temp > summary.TemperatureMax {
+			summary.TemperatureMax = temp
+		}
+		if ts.Data.Next1_Hours != nil {
+			summary.PrecipitationAmount += ts.Data.Next1_Hours.Details.PrecipitationAmount
+		}
+		if summary.SymbolCode == "" {
+			summary.SymbolCode = ts.SymbolCode()
+		}
+	}
+
+	// The "2006-01-02" keys sort lexically in date order
+	sort.Strings(order)
+	summaries := make([]DailySummary, 0, len(order))
+	for _, dateKey := range order {
+		summaries = append(summaries, *summariesByDate[dateKey])
+	}
+	return summaries
+}